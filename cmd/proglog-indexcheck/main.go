@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	plog "github.com/takumi616/proglog/internal/log"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory containing segment .store/.index files")
+	fix := flag.Bool("fix", false, "rewrite mismatched index files instead of only reporting them")
+	flag.Parse()
+
+	entries, err := os.ReadDir(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var cfg plog.Config
+	cfg.Segment.MaxIndexBytes = 1 << 20
+
+	var mismatches int
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".store") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), ".store")
+		if _, err := strconv.ParseUint(base, 10, 64); err != nil {
+			continue
+		}
+
+		storePath := filepath.Join(*dir, e.Name())
+		indexPath := filepath.Join(*dir, base+".index")
+
+		mismatch, err := checkSegment(storePath, indexPath, cfg, *fix)
+		if err != nil {
+			log.Printf("%s: %v", base, err)
+			continue
+		}
+		if mismatch {
+			mismatches++
+			fmt.Printf("%s: index out of sync with store%s\n", base, fixNote(*fix))
+		}
+	}
+
+	if mismatches > 0 && !*fix {
+		os.Exit(1)
+	}
+}
+
+func fixNote(fix bool) string {
+	if fix {
+		return " (rebuilt)"
+	}
+	return " (run with -fix to rebuild)"
+}
+
+// checkSegment rebuilds indexPath from storePath and reports whether the
+// result differs from what's currently on disk. When fix is false, the
+// original index is restored after the comparison (or removed if there was
+// none to begin with); when true, the rebuilt index is left in place.
+func checkSegment(storePath, indexPath string, cfg plog.Config, fix bool) (mismatch bool, err error) {
+	original, readErr := os.ReadFile(indexPath)
+	if readErr != nil && !os.IsNotExist(readErr) {
+		return false, readErr
+	}
+	hadIndex := !os.IsNotExist(readErr)
+
+	storeFile, err := os.OpenFile(storePath, os.O_RDONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer storeFile.Close()
+
+	indexFile, err := os.OpenFile(indexPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer indexFile.Close()
+
+	if err := plog.RebuildIndex(storeFile, indexFile, cfg); err != nil {
+		return false, err
+	}
+
+	rebuilt, err := os.ReadFile(indexPath)
+	if err != nil {
+		return false, err
+	}
+
+	mismatch = !bytes.Equal(original, rebuilt)
+	if !fix && (mismatch || !hadIndex) {
+		// Checking (not fixing) a segment must leave the filesystem exactly
+		// as found: restore the original bytes, or remove the index
+		// entirely if there wasn't one to begin with.
+		if hadIndex {
+			if err := os.WriteFile(indexPath, original, 0644); err != nil {
+				return false, err
+			}
+		} else if err := os.Remove(indexPath); err != nil {
+			return false, err
+		}
+	}
+	return mismatch, nil
+}
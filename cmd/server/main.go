@@ -1,12 +1,37 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"net"
 
+	plog "github.com/takumi616/proglog/internal/log"
 	"github.com/takumi616/proglog/internal/server"
 )
 
 func main() {
-	srv := server.NewHTTPServer(":8080")
+	dataDir := flag.String("data-dir", "/tmp/proglog", "directory the log's segment files are stored in")
+	grpcAddr := flag.String("grpc-addr", ":8081", "address the gRPC server listens on")
+	httpAddr := flag.String("http-addr", ":8080", "address the HTTP server listens on")
+	flag.Parse()
+
+	clog, err := plog.NewLog(*dataDir, plog.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gsrv, err := server.NewGRPCServer(clog)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", *grpcAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		log.Fatal(gsrv.Serve(lis))
+	}()
+
+	srv := server.NewHTTPServer(*httpAddr)
 	log.Fatal(srv.ListenAndServe())
 }
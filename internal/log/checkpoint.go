@@ -0,0 +1,75 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	api "github.com/takumi616/proglog/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkpointStateFile is the name of the compacted state file written inside
+// each checkpoint.NNNN directory.
+const checkpointStateFile = "state"
+
+// Checkpoint compacts every sealed segment whose records fall entirely at or
+// below upTo into a single checkpoint.NNNN directory and deletes those
+// segments, bounding disk usage while leaving a snapshot a new replica can
+// load before tailing the live stream. api.Record carries no key, so the
+// compacted state is simply the last record seen rather than a per-key
+// merge. The active segment is never checkpointed, even if its records
+// would otherwise qualify.
+func (l *Log) Checkpoint(upTo uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var (
+		toCheckpoint []*segment
+		remaining    []*segment
+	)
+	for _, s := range l.segments {
+		if s == l.activeSegment || s.nextOffset > upTo+1 {
+			remaining = append(remaining, s)
+			continue
+		}
+		toCheckpoint = append(toCheckpoint, s)
+	}
+	if len(toCheckpoint) == 0 {
+		return nil
+	}
+
+	last := toCheckpoint[len(toCheckpoint)-1]
+	// readRaw, not Read: the final record may itself be tombstoned, and a
+	// deleted record's value is still the correct compacted state to retain.
+	state, err := last.readRaw(last.nextOffset - 1)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(l.Dir, fmt.Sprintf("checkpoint.%010d", upTo))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeCheckpointState(dir, state); err != nil {
+		return err
+	}
+
+	for _, s := range toCheckpoint {
+		if err := s.Remove(); err != nil {
+			return err
+		}
+	}
+	l.segments = remaining
+
+	return nil
+}
+
+// writeCheckpointState persists record as the compacted state for a checkpoint directory.
+func writeCheckpointState(dir string, record *api.Record) error {
+	p, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, checkpointStateFile), p, 0644)
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionMode selects how a sealed segment's store file is encoded on
+// disk. The active segment is never compressed, only ones that have rolled.
+type CompressionMode uint8
+
+const (
+	CompressionNone CompressionMode = iota
+	CompressionSnappy
+	CompressionZstd
+)
+
+// compress encodes p according to mode.
+func compress(mode CompressionMode, p []byte) ([]byte, error) {
+	switch mode {
+	case CompressionNone:
+		return p, nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, p), nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(p, nil), nil
+	default:
+		return nil, fmt.Errorf("log: unknown compression mode %d", mode)
+	}
+}
+
+// decompress reverses compress.
+func decompress(mode CompressionMode, p []byte) ([]byte, error) {
+	switch mode {
+	case CompressionNone:
+		return p, nil
+	case CompressionSnappy:
+		return snappy.Decode(nil, p)
+	case CompressionZstd:
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(p, nil)
+	default:
+		return nil, fmt.Errorf("log: unknown compression mode %d", mode)
+	}
+}
@@ -0,0 +1,14 @@
+package log
+
+// Config bundles the tunables shared by a log's segments.
+type Config struct {
+	Segment struct {
+		MaxStoreBytes uint64
+		MaxIndexBytes uint64
+		InitialOffset uint64
+		// Compression controls how a segment's store file is encoded once it's
+		// sealed. It has no effect on the active segment, which is always
+		// written and read uncompressed.
+		Compression CompressionMode
+	}
+}
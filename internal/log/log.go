@@ -0,0 +1,232 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	api "github.com/takumi616/proglog/api/v1"
+)
+
+// ErrOffsetOutOfRange is returned by Log.Read for an offset not (yet) held
+// by any segment, as distinct from one that was read and found tombstoned
+// (ErrDeleted) or failed to read for some other reason.
+var ErrOffsetOutOfRange = errors.New("log: offset out of range")
+
+// Log is an ordered sequence of segments, rolling over to a fresh active
+// segment whenever the current one is maxed out.
+type Log struct {
+	mu sync.RWMutex
+
+	Dir    string
+	Config Config
+
+	activeSegment *segment
+	segments      []*segment
+}
+
+// NewLog opens (or creates) a Log rooted at dir, recovering any segments
+// already on disk.
+func NewLog(dir string, c Config) (*Log, error) {
+	if c.Segment.MaxStoreBytes == 0 {
+		c.Segment.MaxStoreBytes = 1024
+	}
+	if c.Segment.MaxIndexBytes == 0 {
+		c.Segment.MaxIndexBytes = 1024
+	}
+	l := &Log{
+		Dir:    dir,
+		Config: c,
+	}
+	return l, l.setup()
+}
+
+// setup discovers existing segments under l.Dir and opens them in order,
+// creating a first segment if the directory was empty.
+func (l *Log) setup() error {
+	files, err := os.ReadDir(l.Dir)
+	if err != nil {
+		return err
+	}
+
+	// Each segment owns several files (.store, .index, .tombstones, ...) that
+	// all share its baseOffset stem, so dedupe by stem rather than assuming a
+	// fixed file count per segment.
+	seen := make(map[uint64]bool)
+	var baseOffsets []uint64
+	for _, file := range files {
+		offStr := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		off, err := strconv.ParseUint(offStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		if seen[off] {
+			continue
+		}
+		seen[off] = true
+		baseOffsets = append(baseOffsets, off)
+	}
+	sort.Slice(baseOffsets, func(i, j int) bool { return baseOffsets[i] < baseOffsets[j] })
+
+	for _, off := range baseOffsets {
+		if err := l.newSegment(off); err != nil {
+			return err
+		}
+	}
+
+	if l.segments == nil {
+		if err := l.newSegment(l.Config.Segment.InitialOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Append appends record to the active segment, assigning it the next
+// offset, rolling to a new active segment afterward if it's now maxed.
+func (l *Log) Append(record *api.Record) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	off, err := l.activeSegment.Append(record)
+	if err != nil {
+		return 0, err
+	}
+	if l.activeSegment.IsMaxed() {
+		err = l.rollActiveSegment()
+	}
+	return off, err
+}
+
+// AppendAt appends record preserving its externally assigned offset (see
+// segment.AppendAt), used by a Replicator following a leader.
+func (l *Log) AppendAt(record *api.Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.activeSegment.AppendAt(record); err != nil {
+		return err
+	}
+	if l.activeSegment.IsMaxed() {
+		return l.rollActiveSegment()
+	}
+	return nil
+}
+
+// rollActiveSegment opens a fresh active segment and seals the one it
+// replaces, applying compression per l.Config if configured.
+func (l *Log) rollActiveSegment() error {
+	sealed := l.activeSegment
+	if err := l.newSegment(l.activeSegment.nextOffset); err != nil {
+		return err
+	}
+	return sealed.Seal()
+}
+
+// Read returns the record stored at off.
+func (l *Log) Read(off uint64) (*api.Record, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var s *segment
+	for _, segment := range l.segments {
+		if segment.baseOffset <= off && off < segment.nextOffset {
+			s = segment
+			break
+		}
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w: %d", ErrOffsetOutOfRange, off)
+	}
+	return s.Read(off)
+}
+
+// Wait returns a channel closed the next time a record is appended to the
+// active segment, satisfying the tailWaiter interface for ConsumeStream.
+func (l *Log) Wait() <-chan struct{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.activeSegment.Wait()
+}
+
+// LowestOffset returns the first offset retained by the log.
+func (l *Log) LowestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.segments[0].baseOffset, nil
+}
+
+// HighestOffset returns the offset of the most recently appended record.
+func (l *Log) HighestOffset() (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	off := l.segments[len(l.segments)-1].nextOffset
+	if off == 0 {
+		return 0, nil
+	}
+	return off - 1, nil
+}
+
+// Close closes every segment in the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, segment := range l.segments {
+		if err := segment.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Truncate drops every segment entirely below lowest, bounding disk usage
+// for operators who don't need a full checkpoint/compaction pass.
+func (l *Log) Truncate(lowest uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var segments []*segment
+	for _, s := range l.segments {
+		if s != l.activeSegment && s.nextOffset <= lowest {
+			if err := s.Remove(); err != nil {
+				return err
+			}
+			continue
+		}
+		segments = append(segments, s)
+	}
+	l.segments = segments
+	return nil
+}
+
+// Remove closes the log and deletes its directory.
+func (l *Log) Remove() error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(l.Dir)
+}
+
+// Reset removes the log and reinitializes it from an empty directory.
+func (l *Log) Reset() error {
+	if err := l.Remove(); err != nil {
+		return err
+	}
+	return l.setup()
+}
+
+func (l *Log) newSegment(off uint64) error {
+	s, err := newSegment(l.Dir, off, l.Config)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, s)
+	l.activeSegment = s
+	return nil
+}
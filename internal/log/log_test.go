@@ -0,0 +1,199 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/takumi616/proglog/api/v1"
+)
+
+func TestLog_TruncateKeepsActiveSegment(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 32
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte("record")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	high, err := l.HighestOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncating up to the current high-water mark is the normal "drop
+	// everything below the head" call; it must never remove the segment
+	// still being appended to.
+	if err := l.Truncate(high); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l.segments) == 0 {
+		t.Fatal("Truncate removed every segment, including the active one")
+	}
+
+	if _, err := l.Append(&api.Record{Value: []byte("more")}); err != nil {
+		t.Fatalf("Append after Truncate: %v", err)
+	}
+}
+
+func TestLog_CheckpointCompactsSealedSegments(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 32
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte("record")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := len(l.segments)
+	active := l.activeSegment
+
+	high, err := l.HighestOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Checkpoint(high); err != nil {
+		t.Fatal(err)
+	}
+
+	if l.activeSegment != active {
+		t.Fatal("Checkpoint must never replace or remove the active segment")
+	}
+	if len(l.segments) >= before {
+		t.Fatalf("got %d segments after Checkpoint, want fewer than %d", len(l.segments), before)
+	}
+}
+
+func TestLog_TruncateDropsOnlySegmentsBelowBoundary(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 48
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Each record takes 16-18 store bytes (8-byte length prefix plus the
+	// encoded record), so with MaxStoreBytes = 48 the segment rolls every
+	// 3 appends, and 7 records yields three segments: [0,3), [3,6), [6,7).
+	for i := 0; i < 7; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte("record")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l.segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(l.segments))
+	}
+
+	if err := l.Truncate(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l.segments) != 2 {
+		t.Fatalf("got %d segments after Truncate(3), want 2 (only the [0,3) segment should drop)", len(l.segments))
+	}
+	lowest, err := l.LowestOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lowest != 3 {
+		t.Fatalf("LowestOffset() = %d, want 3", lowest)
+	}
+	if _, err := l.Read(2); err == nil {
+		t.Fatal("Read(2) succeeded, want an error: its segment should have been dropped")
+	}
+	if _, err := l.Read(3); err != nil {
+		t.Fatalf("Read(3) = %v, want nil: its segment must be kept", err)
+	}
+}
+
+func TestLog_CheckpointCompactsOnlySegmentsBelowBoundary(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 48
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Same layout as above: three segments covering [0,3), [3,6), [6,7).
+	for i := 0; i < 7; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte("record")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Checkpoint(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l.segments) != 2 {
+		t.Fatalf("got %d segments after Checkpoint(2), want 2 (only the [0,3) segment should compact)", len(l.segments))
+	}
+	lowest, err := l.LowestOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lowest != 3 {
+		t.Fatalf("LowestOffset() = %d, want 3 (the [3,6) segment must survive)", lowest)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkpoint.0000000002")); err != nil {
+		t.Fatalf("checkpoint directory not created: %v", err)
+	}
+}
+
+func TestLog_SetupDedupesSegmentFiles(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(&api.Record{Value: []byte("hi")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The .store, .index, and .tombstones files all share one base-offset
+	// stem; setup() must open one segment per stem, not one per file.
+	reopened, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if len(reopened.segments) != 1 {
+		t.Fatalf("got %d segments after restart, want 1", len(reopened.segments))
+	}
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"io"
+	"os"
+
+	api "github.com/takumi616/proglog/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// RebuildIndex streams storeFile sequentially and rewrites indexFile from
+// scratch with one (relativeOffset, position) entry per record found. It
+// reads an active store's plain length-prefix framing or, if storeFile
+// begins with storeMagic, a sealed store's compressed frames. The first
+// record's offset is taken as the segment's base offset. It's used to
+// recover a segment whose index is missing or corrupt, and by
+// cmd/proglog-indexcheck.
+func RebuildIndex(storeFile, indexFile *os.File, c Config) error {
+	if err := indexFile.Truncate(0); err != nil {
+		return err
+	}
+
+	idx, err := newIndex(indexFile, c)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	storeInfo, err := storeFile.Stat()
+	if err != nil {
+		return err
+	}
+	sealed, compression, err := detectSealedHeader(storeFile, uint64(storeInfo.Size()))
+	if err != nil {
+		return err
+	}
+
+	var (
+		baseOffset uint64
+		seenFirst  bool
+		pos        uint64
+	)
+	if sealed {
+		pos = 2
+	}
+
+	for {
+		framePos := pos
+
+		var recBuf []byte
+		if sealed {
+			recBuf, pos, err = readSealedFrame(storeFile, pos, compression)
+		} else {
+			recBuf, pos, err = readActiveFrame(storeFile, pos)
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		record := &api.Record{}
+		if err := proto.Unmarshal(recBuf, record); err != nil {
+			return err
+		}
+
+		if !seenFirst {
+			baseOffset = record.Offset
+			seenFirst = true
+		}
+
+		if err := idx.Write(uint32(record.Offset-baseOffset), framePos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readActiveFrame reads the length-prefixed record at pos from an
+// uncompressed, active-layout store and returns it along with the position
+// immediately following it.
+func readActiveFrame(f *os.File, pos uint64) (record []byte, next uint64, err error) {
+	lenBuf := make([]byte, lenWidth)
+	if _, err := f.ReadAt(lenBuf, int64(pos)); err != nil {
+		return nil, 0, err
+	}
+	size := enc.Uint64(lenBuf)
+
+	recBuf := make([]byte, size)
+	if _, err := f.ReadAt(recBuf, int64(pos)+lenWidth); err != nil {
+		return nil, 0, err
+	}
+
+	return recBuf, pos + lenWidth + size, nil
+}
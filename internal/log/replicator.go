@@ -0,0 +1,207 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	api "github.com/takumi616/proglog/api/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// replicaAppender is the subset of *Log a Replicator writes replicated
+// records into. *Log implements it by routing to its active segment's
+// AppendAt, which preserves the leader-assigned offset instead of renumbering it.
+type replicaAppender interface {
+	AppendAt(*api.Record) error
+}
+
+// Replicator follows other proglog nodes, pulling their records over a
+// streaming Consume RPC and appending them to a local replicaAppender. It
+// runs one goroutine per peer joined via Join.
+type Replicator struct {
+	DialOptions []grpc.DialOption
+	LocalLog    replicaAppender
+	// DataDir is where each peer's replicated offset is persisted so
+	// replication can resume after a restart instead of starting over.
+	DataDir string
+	Logger  *zap.Logger
+
+	mu      sync.Mutex
+	servers map[string]chan struct{}
+	closed  bool
+	close   chan struct{}
+}
+
+// Join starts replicating from the peer at addr, known locally as name. It's
+// a no-op if name is already being replicated.
+func (r *Replicator) Join(name, addr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.init()
+
+	if r.closed {
+		return nil
+	}
+
+	if _, ok := r.servers[name]; ok {
+		// already replicating so skip
+		return nil
+	}
+	leave := make(chan struct{})
+	r.servers[name] = leave
+
+	go r.replicate(name, addr, leave)
+
+	return nil
+}
+
+// replicate dials addr, tails its log from the last persisted offset, and
+// appends each received record locally until leave or r.close fires.
+func (r *Replicator) replicate(name, addr string, leave chan struct{}) {
+	cc, err := grpc.Dial(addr, r.DialOptions...)
+	if err != nil {
+		r.logError(err, "failed to dial", addr)
+		return
+	}
+	defer cc.Close()
+
+	client := api.NewLogClient(cc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.ConsumeStream(
+		ctx,
+		&api.ConsumeRequest{Offset: r.loadOffset(name)},
+	)
+	if err != nil {
+		r.logError(err, "failed to consume", addr)
+		return
+	}
+
+	records := make(chan *api.Record)
+	go func() {
+		for {
+			recv, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF {
+					r.logError(err, "failed to receive", addr)
+				}
+				close(records)
+				return
+			}
+			records <- recv.Record
+		}
+	}()
+
+	for {
+		select {
+		case <-r.close:
+			return
+		case <-leave:
+			return
+		case record, ok := <-records:
+			if !ok {
+				return
+			}
+			if err = r.LocalLog.AppendAt(record); err != nil {
+				r.logError(err, "failed to append", addr)
+				return
+			}
+			if err = r.saveOffset(name, record.Offset+1); err != nil {
+				r.logError(err, "failed to persist offset", addr)
+				return
+			}
+		}
+	}
+}
+
+// Leave stops replicating from the peer known locally as name.
+func (r *Replicator) Leave(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+
+	if _, ok := r.servers[name]; !ok {
+		return nil
+	}
+	close(r.servers[name])
+	delete(r.servers, name)
+	return nil
+}
+
+// init lazily prepares the Replicator's internal state.
+func (r *Replicator) init() {
+	if r.Logger == nil {
+		r.Logger = zap.L().Named("replicator")
+	}
+	if r.servers == nil {
+		r.servers = make(map[string]chan struct{})
+	}
+	if r.close == nil {
+		r.close = make(chan struct{})
+	}
+}
+
+// Close stops replicating from every peer and shuts the Replicator down.
+func (r *Replicator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.close)
+	return nil
+}
+
+func (r *Replicator) logError(err error, msg, addr string) {
+	r.Logger.Error(
+		msg,
+		zap.String("addr", addr),
+		zap.Error(err),
+	)
+}
+
+// offsetPath returns where name's replicated offset is persisted.
+func (r *Replicator) offsetPath(name string) string {
+	return filepath.Join(r.DataDir, name+".offset")
+}
+
+// loadOffset returns the next offset to fetch from name, or 0 if nothing has
+// been replicated from it yet.
+func (r *Replicator) loadOffset(name string) uint64 {
+	if r.DataDir == "" {
+		return 0
+	}
+	b, err := os.ReadFile(r.offsetPath(name))
+	if err != nil {
+		return 0
+	}
+	var offset uint64
+	if err := json.Unmarshal(b, &offset); err != nil {
+		return 0
+	}
+	return offset
+}
+
+// saveOffset persists the next offset to fetch from name so replication can
+// resume from there after a restart instead of re-requesting records already applied.
+func (r *Replicator) saveOffset(name string, offset uint64) error {
+	if r.DataDir == "" {
+		return nil
+	}
+	b, err := json.Marshal(offset)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.offsetPath(name), b, 0644)
+}
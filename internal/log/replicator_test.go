@@ -0,0 +1,85 @@
+package log
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/takumi616/proglog/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeConsumeServer is a minimal api.LogServer that streams a fixed list of
+// records over ConsumeStream starting at the client's requested offset, then
+// blocks until the stream's context is done.
+type fakeConsumeServer struct {
+	api.UnimplementedLogServer
+	records []*api.Record
+}
+
+func (s *fakeConsumeServer) ConsumeStream(req *api.ConsumeRequest, stream api.Log_ConsumeStreamServer) error {
+	for off := req.Offset; off < uint64(len(s.records)); off++ {
+		if err := stream.Send(&api.ConsumeResponse{Record: s.records[off]}); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// fakeAppender is a minimal replicaAppender that just records what it's given.
+type fakeAppender struct {
+	mu      sync.Mutex
+	records []*api.Record
+}
+
+func (f *fakeAppender) AppendAt(r *api.Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records = append(f.records, r)
+	return nil
+}
+
+func TestReplicator_PersistsNextOffsetToFetch(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsrv := grpc.NewServer()
+	api.RegisterLogServer(gsrv, &fakeConsumeServer{records: []*api.Record{
+		{Offset: 0, Value: []byte("a")},
+		{Offset: 1, Value: []byte("b")},
+	}})
+	go gsrv.Serve(lis)
+	defer gsrv.Stop()
+
+	r := &Replicator{
+		DialOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		LocalLog:    &fakeAppender{},
+		DataDir:     t.TempDir(),
+	}
+	defer r.Close()
+
+	if err := r.Join("peer", lis.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Poll the persisted offset rather than the appender's record count: the
+	// bug under test is specifically about what gets written to disk once
+	// both records have been applied.
+	deadline := time.Now().Add(2 * time.Second)
+	for r.loadOffset("peer") != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting to replicate; loadOffset(peer) = %d, want 2", r.loadOffset("peer"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A restart must resume from the next unreplicated offset (2), not
+	// re-request offset 1, which AppendAt would reject as already applied.
+	if got := r.loadOffset("peer"); got != 2 {
+		t.Fatalf("loadOffset(peer) = %d, want 2", got)
+	}
+}
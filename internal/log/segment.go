@@ -1,9 +1,11 @@
 package log
 
 import (
+	"encoding/binary"
 	"fmt"
 	"os"
 	"path"
+	"sync"
 
 	api "github.com/takumi616/proglog/api/v1"
 	"google.golang.org/protobuf/proto"
@@ -12,10 +14,14 @@ import (
 // Represent a continuous sequence of records in the log.
 // Include a store file (record bytes) and an index file (offset → position mappings).
 type segment struct {
-	store                  *store // The store file that holds the raw record bytes
-	index                  *index // The index file that maps logical offsets to store positions
-	baseOffset, nextOffset uint64 // baseOffset is the first offset in this segment, nextOffset is the next available one
-	config                 Config // Configuration settings for size limits, etc.
+	store                  *store      // The store file that holds the raw record bytes
+	index                  *index      // The index file that maps logical offsets to store positions
+	tombstones             *tombstones // Deleted-offset intervals consulted by Read
+	baseOffset, nextOffset uint64      // baseOffset is the first offset in this segment, nextOffset is the next available one
+	config                 Config      // Configuration settings for size limits, etc.
+
+	mu       sync.Mutex    // Guards notifyCh against concurrent Append/Wait calls
+	notifyCh chan struct{} // Closed and replaced on every Append to wake up waiters
 }
 
 // Initialize a new segment by creating or opening the store and index files.
@@ -52,8 +58,44 @@ func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
 		return nil, err
 	}
 
-	// Determine the nextOffset based on the index contents
-	if off, _, err := s.index.Read(-1); err != nil {
+	// Open or create the tombstones file for this segment
+	tombstonesFile, err := os.OpenFile(
+		path.Join(dir, fmt.Sprintf("%d%s", baseOffset, ".tombstones")),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if s.tombstones, err = newTombstones(tombstonesFile); err != nil {
+		return nil, err
+	}
+
+	s.notifyCh = make(chan struct{})
+
+	// A non-empty store with an empty index means the index was lost or
+	// never flushed; rebuild it from the store before trusting it below.
+	storeInfo, err := os.Stat(storeFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	if storeInfo.Size() > 0 && s.index.size == 0 {
+		if err := s.recoverIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Determine the nextOffset based on the index contents, rebuilding the
+	// index from the store if its last entry points past the end of the
+	// store (a sign the index itself is corrupt).
+	off, pos, err := s.index.Read(-1)
+	if err == nil && pos >= uint64(storeInfo.Size()) && storeInfo.Size() > 0 {
+		if err := s.recoverIndex(); err != nil {
+			return nil, err
+		}
+		off, _, err = s.index.Read(-1)
+	}
+	if err != nil {
 		s.nextOffset = baseOffset
 	} else {
 		s.nextOffset = baseOffset + uint64(off) + 1
@@ -61,37 +103,108 @@ func newSegment(dir string, baseOffset uint64, c Config) (*segment, error) {
 	return s, nil
 }
 
-// Append a record to the segment and returns its offset.
+// recoverIndex rebuilds this segment's index from its store file, used when
+// the index is missing, truncated, or otherwise out of sync with the store
+// so the segment doesn't silently reset nextOffset back to baseOffset.
+func (s *segment) recoverIndex() error {
+	if err := s.index.Close(); err != nil {
+		return err
+	}
+
+	indexFile, err := os.OpenFile(s.index.Name(), os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if err := RebuildIndex(s.store.File, indexFile, s.config); err != nil {
+		return err
+	}
+
+	reopened, err := os.OpenFile(s.index.Name(), os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	s.index, err = newIndex(reopened, s.config)
+	return err
+}
+
+// Append a record to the segment, assigning it the next available offset,
+// and returns that offset.
 func (s *segment) Append(record *api.Record) (offset uint64, err error) {
-	cur := s.nextOffset
-	record.Offset = cur
+	record.Offset = s.nextOffset
+	if err = s.append(record); err != nil {
+		return 0, err
+	}
+	return record.Offset, nil
+}
+
+// AppendAt appends a record whose offset was already assigned upstream (for
+// example by a replication leader), preserving it instead of renumbering.
+// It rejects records that would leave a gap in this segment's offsets.
+func (s *segment) AppendAt(record *api.Record) error {
+	if record.Offset != s.nextOffset {
+		return fmt.Errorf(
+			"segment: out-of-order replicated offset %d, expected %d",
+			record.Offset, s.nextOffset,
+		)
+	}
+	return s.append(record)
+}
 
+// append serializes record and writes it to the store and index, advancing nextOffset.
+func (s *segment) append(record *api.Record) error {
 	// Serialize the record using Protobuf
 	p, err := proto.Marshal(record)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	// Append to store and get the position
 	_, pos, err := s.store.Append(p)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	// Write offset (relative to baseOffset) and position to the index
 	if err = s.index.Write(
-		uint32(s.nextOffset-s.baseOffset),
+		uint32(record.Offset-s.baseOffset),
 		pos,
 	); err != nil {
-		return 0, err
+		return err
 	}
 
 	s.nextOffset++
-	return cur, nil
+	s.notifyAppend()
+	return nil
+}
+
+// Wake up any goroutines blocked in Wait, letting them re-check for new records.
+func (s *segment) notifyAppend() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.notifyCh)
+	s.notifyCh = make(chan struct{})
+}
+
+// Wait returns a channel that is closed the next time Append is called on this
+// segment, so a tailing reader can block until a new record arrives instead of polling.
+func (s *segment) Wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notifyCh
 }
 
-// Retrieve a record by its absolute offset.
+// Retrieve a record by its absolute offset. Returns ErrDeleted if off falls
+// within a tombstoned range instead of the (stale) record data.
 func (s *segment) Read(off uint64) (*api.Record, error) {
+	if s.tombstones.Deleted(off) {
+		return nil, ErrDeleted
+	}
+	return s.readRaw(off)
+}
+
+// readRaw reads the record at off ignoring tombstones, used internally by
+// Seal so sealing doesn't lose records that are merely marked deleted.
+func (s *segment) readRaw(off uint64) (*api.Record, error) {
 	// Get the store position from index (using relative offset)
 	_, pos, err := s.index.Read(int64(off - s.baseOffset))
 	if err != nil {
@@ -110,17 +223,147 @@ func (s *segment) Read(off uint64) (*api.Record, error) {
 	return record, err
 }
 
+// Delete marks the record at off as deleted within this segment.
+func (s *segment) Delete(off uint64) error {
+	return s.DeleteRange(off, off)
+}
+
+// DeleteRange marks every record in [min, max] that falls within this
+// segment's offsets as deleted, fsyncing the tombstones file immediately.
+func (s *segment) DeleteRange(min, max uint64) error {
+	if s.nextOffset == 0 {
+		return nil
+	}
+	lo, hi := min, max
+	if lo < s.baseOffset {
+		lo = s.baseOffset
+	}
+	if hi >= s.nextOffset {
+		hi = s.nextOffset - 1
+	}
+	if lo > hi {
+		return nil
+	}
+	s.tombstones.Delete(lo, hi)
+	return s.tombstones.Sync()
+}
+
 // Return true if the segment has reached its maximum size.
 func (s *segment) IsMaxed() bool {
 	return s.store.size >= s.config.Segment.MaxStoreBytes ||
 		s.index.size >= s.config.Segment.MaxIndexBytes
 }
 
+// Seal rewrites this segment's store file as a compressed blob per
+// s.config.Segment.Compression and regenerates the index to match the new
+// record positions. The caller must only seal a segment once it has rolled
+// and stopped receiving Append/AppendAt calls; compressing a still-active
+// segment would corrupt it.
+func (s *segment) Seal() error {
+	if s.config.Segment.Compression == CompressionNone {
+		return nil
+	}
+
+	records := make([]*api.Record, 0, s.nextOffset-s.baseOffset)
+	for off := s.baseOffset; off < s.nextOffset; off++ {
+		record, err := s.readRaw(off)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+
+	tmp, err := os.CreateTemp(path.Dir(s.store.Name()), "sealed-*.store")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write([]byte{storeMagic, byte(s.config.Segment.Compression)}); err != nil {
+		return err
+	}
+
+	type indexEntry struct {
+		relOffset uint32
+		pos       uint64
+	}
+	entries := make([]indexEntry, 0, len(records))
+	pos := uint64(2)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+
+	for _, record := range records {
+		p, err := proto.Marshal(record)
+		if err != nil {
+			return err
+		}
+		compressed, err := compress(s.config.Segment.Compression, p)
+		if err != nil {
+			return err
+		}
+
+		n := binary.PutUvarint(lenBuf, uint64(len(compressed)))
+		if _, err := tmp.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := tmp.Write(compressed); err != nil {
+			return err
+		}
+
+		entries = append(entries, indexEntry{
+			relOffset: uint32(record.Offset - s.baseOffset),
+			pos:       pos,
+		})
+		pos += uint64(n) + uint64(len(compressed))
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := s.store.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.store.Name()); err != nil {
+		return err
+	}
+	storeFile, err := os.OpenFile(s.store.Name(), os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if s.store, err = newStore(storeFile); err != nil {
+		return err
+	}
+
+	if err := s.index.Close(); err != nil {
+		return err
+	}
+	if err := os.Truncate(s.index.Name(), 0); err != nil {
+		return err
+	}
+	indexFile, err := os.OpenFile(s.index.Name(), os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if s.index, err = newIndex(indexFile, s.config); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := s.index.Write(e.relOffset, e.pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Close the segment's store and index files.
 func (s *segment) Close() error {
 	if err := s.index.Close(); err != nil {
 		return err
 	}
+	if err := s.tombstones.Close(); err != nil {
+		return err
+	}
 	if err := s.store.Close(); err != nil {
 		return err
 	}
@@ -135,6 +378,9 @@ func (s *segment) Remove() error {
 	if err := os.Remove(s.index.Name()); err != nil {
 		return err
 	}
+	if err := os.Remove(s.tombstones.Name()); err != nil {
+		return err
+	}
 	if err := os.Remove(s.store.Name()); err != nil {
 		return err
 	}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"testing"
+
+	api "github.com/takumi616/proglog/api/v1"
+)
+
+func TestSegment_SealRoundTrip(t *testing.T) {
+	for _, mode := range []CompressionMode{CompressionNone, CompressionSnappy, CompressionZstd} {
+		mode := mode
+		t.Run(compressionName(mode), func(t *testing.T) {
+			dir := t.TempDir()
+			var c Config
+			c.Segment.MaxStoreBytes = 1024
+			c.Segment.MaxIndexBytes = 1024
+			c.Segment.Compression = mode
+
+			s, err := newSegment(dir, 0, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := [][]byte{[]byte("hello"), []byte("world"), []byte("proglog")}
+			for _, v := range want {
+				if _, err := s.Append(&api.Record{Value: v}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := s.Seal(); err != nil {
+				t.Fatalf("Seal(%s): %v", compressionName(mode), err)
+			}
+
+			for i, v := range want {
+				record, err := s.Read(uint64(i))
+				if err != nil {
+					t.Fatalf("Read(%d): %v", i, err)
+				}
+				if string(record.Value) != string(v) {
+					t.Fatalf("Read(%d).Value = %q, want %q", i, record.Value, v)
+				}
+				if record.Offset != uint64(i) {
+					t.Fatalf("Read(%d).Offset = %d, want %d", i, record.Offset, i)
+				}
+			}
+		})
+	}
+}
+
+func TestSegment_AppendRejectsSealedStore(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = 1024
+	c.Segment.Compression = CompressionSnappy
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(&api.Record{Value: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Seal(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Append(&api.Record{Value: []byte("second")}); err == nil {
+		t.Fatal("Append into a sealed, compressed segment should fail")
+	}
+}
+
+func compressionName(mode CompressionMode) string {
+	switch mode {
+	case CompressionNone:
+		return "none"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return "unknown"
+	}
+}
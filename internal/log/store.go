@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	enc = binary.BigEndian
+)
+
+const (
+	lenWidth = 8
+	// storeMagic marks offset 0 of a sealed, compressed store file. An active
+	// (unsealed) store has no such header: offset 0 is simply the 8-byte
+	// big-endian length of its first record, whose high byte is 0 for any
+	// record under 2^56 bytes, so the two layouts can't collide in practice.
+	storeMagic byte = 0xF5
+)
+
+// Represent the append-only file a segment stores its raw record bytes in.
+type store struct {
+	*os.File
+	mu   sync.Mutex
+	buf  *bufio.Writer
+	size uint64
+
+	// sealed and compression describe a store that's been rewritten in
+	// compressed form by segment.Seal; they're detected from storeMagic
+	// the first time the file is opened.
+	sealed      bool
+	compression CompressionMode
+}
+
+// Create a new store backed by f, detecting whether it's already a sealed,
+// compressed store from its header.
+func newStore(f *os.File) (*store, error) {
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(fi.Size())
+
+	s := &store{
+		File: f,
+		size: size,
+		buf:  bufio.NewWriter(f),
+	}
+
+	sealed, compression, err := detectSealedHeader(f, size)
+	if err != nil {
+		return nil, err
+	}
+	s.sealed = sealed
+	s.compression = compression
+
+	return s, nil
+}
+
+// detectSealedHeader reports whether f begins with storeMagic, and if so the
+// compression mode recorded alongside it. Used both by newStore and by
+// RebuildIndex, which has to parse a sealed store's frames differently from
+// an active one's.
+func detectSealedHeader(f *os.File, size uint64) (sealed bool, compression CompressionMode, err error) {
+	if size < 2 {
+		return false, 0, nil
+	}
+	header := make([]byte, 2)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return false, 0, err
+	}
+	if header[0] != storeMagic {
+		return false, 0, nil
+	}
+	return true, CompressionMode(header[1]), nil
+}
+
+// Append a length-prefixed record to the store and return the number of
+// bytes written and the position at which the record begins.
+func (s *store) Append(p []byte) (n uint64, pos uint64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sealed {
+		return 0, 0, fmt.Errorf("store: cannot append to a sealed, compressed store")
+	}
+
+	pos = s.size
+	if err := binary.Write(s.buf, enc, uint64(len(p))); err != nil {
+		return 0, 0, err
+	}
+
+	w, err := s.buf.Write(p)
+	if err != nil {
+		return 0, 0, err
+	}
+	w += lenWidth
+	s.size += uint64(w)
+
+	return uint64(w), pos, nil
+}
+
+// Read the record stored at pos.
+func (s *store) Read(pos uint64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.buf.Flush(); err != nil {
+		return nil, err
+	}
+
+	if s.sealed {
+		return s.readSealed(pos)
+	}
+
+	size := make([]byte, lenWidth)
+	if _, err := s.File.ReadAt(size, int64(pos)); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, enc.Uint64(size))
+	if _, err := s.File.ReadAt(b, int64(pos+lenWidth)); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// readSealed reads a varint-length-prefixed, compressed record at pos from a
+// sealed store and transparently decompresses it.
+func (s *store) readSealed(pos uint64) ([]byte, error) {
+	p, _, err := readSealedFrame(s.File, pos, s.compression)
+	return p, err
+}
+
+// readSealedFrame reads the varint-length-prefixed, compressed record at pos
+// from a sealed store's file and returns its decompressed payload along with
+// the position immediately following the frame.
+func readSealedFrame(f *os.File, pos uint64, compression CompressionMode) (payload []byte, next uint64, err error) {
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	read, err := f.ReadAt(lenBuf, int64(pos))
+	if err != nil && !(errors.Is(err, io.EOF) && read > 0) {
+		// ReadAt returns a non-nil error (typically io.EOF) whenever the file
+		// ends before filling lenBuf, even though the few bytes it did read
+		// may be a perfectly valid varint for the store's final frame. Only
+		// treat this as fatal when nothing at all was read.
+		return nil, 0, err
+	}
+	lenBuf = lenBuf[:read]
+	frameLen, n := binary.Uvarint(lenBuf)
+	if n <= 0 {
+		return nil, 0, fmt.Errorf("store: corrupt compressed frame at position %d", pos)
+	}
+
+	compressed := make([]byte, frameLen)
+	if _, err := f.ReadAt(compressed, int64(pos)+int64(n)); err != nil {
+		return nil, 0, err
+	}
+
+	p, err := decompress(compression, compressed)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, pos + uint64(n) + frameLen, nil
+}
+
+// ReadAt implements io.ReaderAt over the store, flushing buffered writes first.
+func (s *store) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.buf.Flush(); err != nil {
+		return 0, err
+	}
+	return s.File.ReadAt(p, off)
+}
+
+// Close flushes any buffered writes and closes the underlying file.
+func (s *store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	return s.File.Close()
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrDeleted is returned by segment.Read (and surfaced through Log.Read) for
+// a record that falls within a tombstoned range.
+var ErrDeleted = errors.New("log: record deleted")
+
+// interval is an inclusive [start, end] run of deleted offsets.
+type interval struct {
+	start, end uint64
+}
+
+// tombstones tracks deleted-record ranges for one segment: a sorted, merged
+// run of intervals kept in memory for fast lookups, backed by a file on disk
+// so deletions survive a restart.
+type tombstones struct {
+	mu        sync.RWMutex
+	file      *os.File
+	intervals []interval
+}
+
+// newTombstones loads any intervals already persisted in f.
+func newTombstones(f *os.File) (*tombstones, error) {
+	t := &tombstones{file: f}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// load reads the (start, end) uint64 pairs persisted in the tombstones file.
+func (t *tombstones) load() error {
+	fi, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, fi.Size())
+	if _, err := t.file.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return err
+	}
+
+	for i := 0; i+16 <= len(buf); i += 16 {
+		t.intervals = append(t.intervals, interval{
+			start: enc.Uint64(buf[i : i+8]),
+			end:   enc.Uint64(buf[i+8 : i+16]),
+		})
+	}
+	return nil
+}
+
+// Delete marks [start, end] as deleted, merging it with any overlapping or
+// directly adjacent interval already recorded.
+func (t *tombstones) Delete(start, end uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.intervals = append(t.intervals, interval{start, end})
+	sort.Slice(t.intervals, func(i, j int) bool {
+		return t.intervals[i].start < t.intervals[j].start
+	})
+
+	merged := t.intervals[:1]
+	for _, iv := range t.intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv.start <= last.end+1 {
+			if iv.end > last.end {
+				last.end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	t.intervals = merged
+}
+
+// Deleted reports whether off falls within a deleted interval.
+func (t *tombstones) Deleted(off uint64) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	i := sort.Search(len(t.intervals), func(i int) bool {
+		return t.intervals[i].end >= off
+	})
+	return i < len(t.intervals) && t.intervals[i].start <= off
+}
+
+// Sync flushes the current intervals to disk and fsyncs the file.
+func (t *tombstones) Sync() error {
+	// Lock, not RLock: Truncate+WriteAt below is a non-atomic read-modify-write
+	// of the file, and concurrent Syncs (e.g. from overlapping DeleteRange
+	// calls) would otherwise interleave and corrupt it on disk.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.file.Truncate(0); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, len(t.intervals)*16)
+	word := make([]byte, 8)
+	for _, iv := range t.intervals {
+		enc.PutUint64(word, iv.start)
+		buf = append(buf, word...)
+		enc.PutUint64(word, iv.end)
+		buf = append(buf, word...)
+	}
+
+	if _, err := t.file.WriteAt(buf, 0); err != nil {
+		return err
+	}
+	return t.file.Sync()
+}
+
+// Close syncs the tombstones to disk and closes the underlying file.
+func (t *tombstones) Close() error {
+	if err := t.Sync(); err != nil {
+		return err
+	}
+	return t.file.Close()
+}
+
+// Name returns the path of the underlying tombstones file.
+func (t *tombstones) Name() string {
+	return t.file.Name()
+}
+
+// Delete marks the record at offset as deleted.
+func (l *Log) Delete(offset uint64) error {
+	return l.DeleteRange(offset, offset)
+}
+
+// DeleteRange marks every record in [min, max] as deleted across whichever
+// segments it spans. Reads for those offsets return ErrDeleted until the
+// records are physically dropped during a later checkpoint.
+func (l *Log) DeleteRange(min, max uint64) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, s := range l.segments {
+		if max < s.baseOffset || min >= s.nextOffset {
+			continue
+		}
+		if err := s.DeleteRange(min, max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
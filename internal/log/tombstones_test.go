@@ -0,0 +1,123 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/takumi616/proglog/api/v1"
+)
+
+func TestTombstones_MergeLookupAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.OpenFile(filepath.Join(dir, "0.tombstones"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ts, err := newTombstones(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.Delete(2, 4)
+	ts.Delete(5, 6) // adjacent to [2,4]; should merge into [2,6]
+	ts.Delete(10, 12)
+
+	for _, off := range []uint64{2, 3, 4, 5, 6, 10, 11, 12} {
+		if !ts.Deleted(off) {
+			t.Errorf("Deleted(%d) = false, want true", off)
+		}
+	}
+	for _, off := range []uint64{0, 1, 7, 8, 9, 13} {
+		if ts.Deleted(off) {
+			t.Errorf("Deleted(%d) = true, want false", off)
+		}
+	}
+	if len(ts.intervals) != 2 {
+		t.Fatalf("len(intervals) = %d, want 2 (adjacent deletes should merge)", len(ts.intervals))
+	}
+
+	if err := ts.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopenedFile, err := os.OpenFile(filepath.Join(dir, "0.tombstones"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopenedFile.Close()
+
+	reloaded, err := newTombstones(reopenedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Deleted(11) {
+		t.Fatal("tombstones did not survive Sync + reload")
+	}
+	if reloaded.Deleted(9) {
+		t.Fatal("reloaded tombstones marked a never-deleted offset as deleted")
+	}
+}
+
+func TestSegmentRead_ReturnsErrDeletedButSealStillSeesRecord(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 1024
+	c.Segment.MaxIndexBytes = 1024
+
+	s, err := newSegment(dir, 0, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Append(&api.Record{Value: []byte("gone")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Read(0); err != ErrDeleted {
+		t.Fatalf("Read(0) error = %v, want ErrDeleted", err)
+	}
+	if _, err := s.readRaw(0); err != nil {
+		t.Fatalf("readRaw(0) = %v, want nil (Seal must still see tombstoned records)", err)
+	}
+}
+
+func TestLog_DeleteRangeSpansSegments(t *testing.T) {
+	dir := t.TempDir()
+	var c Config
+	c.Segment.MaxStoreBytes = 32
+	c.Segment.MaxIndexBytes = 1024
+
+	l, err := NewLog(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Append(&api.Record{Value: []byte("record")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.DeleteRange(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, off := range []uint64{1, 2, 3} {
+		if _, err := l.Read(off); err != ErrDeleted {
+			t.Fatalf("Read(%d) error = %v, want ErrDeleted", off, err)
+		}
+	}
+	if _, err := l.Read(0); err != nil {
+		t.Fatalf("Read(0) = %v, want nil", err)
+	}
+	if _, err := l.Read(4); err != nil {
+		t.Fatalf("Read(4) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	api "github.com/takumi616/proglog/api/v1"
+	plog "github.com/takumi616/proglog/internal/log"
+	"google.golang.org/grpc"
+)
+
+// CommitLog is the subset of *log.Log the gRPC service depends on, so it can
+// be exercised against a fake in tests instead of a real on-disk log.
+type CommitLog interface {
+	Append(*api.Record) (uint64, error)
+	Read(uint64) (*api.Record, error)
+}
+
+// tailWaiter is implemented by logs that can signal ConsumeStream when a new
+// record is appended, so it can block instead of busy-polling for it.
+type tailWaiter interface {
+	Wait() <-chan struct{}
+}
+
+// pollInterval is how often ConsumeStream falls back to polling when the
+// underlying CommitLog doesn't implement tailWaiter.
+const pollInterval = 100 * time.Millisecond
+
+// grpcServer implements api.LogServer on top of a CommitLog.
+type grpcServer struct {
+	api.UnimplementedLogServer
+	CommitLog CommitLog
+}
+
+// NewGRPCServer builds a *grpc.Server with the log service registered against clog.
+func NewGRPCServer(clog CommitLog, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	gsrv := grpc.NewServer(opts...)
+	api.RegisterLogServer(gsrv, &grpcServer{CommitLog: clog})
+	return gsrv, nil
+}
+
+// Produce appends a single record and returns its assigned offset.
+func (s *grpcServer) Produce(ctx context.Context, req *api.ProduceRequest) (*api.ProduceResponse, error) {
+	offset, err := s.CommitLog.Append(req.Record)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ProduceResponse{Offset: offset}, nil
+}
+
+// Consume returns the record at the requested offset.
+func (s *grpcServer) Consume(ctx context.Context, req *api.ConsumeRequest) (*api.ConsumeResponse, error) {
+	record, err := s.CommitLog.Read(req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ConsumeResponse{Record: record}, nil
+}
+
+// ProduceStream accepts a client-streamed sequence of records and replies
+// with each one's assigned offset as it's appended.
+func (s *grpcServer) ProduceStream(stream api.Log_ProduceStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			// The client called CloseSend after its last record; that's a
+			// clean end of the stream, not an RPC failure.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res, err := s.Produce(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// ConsumeStream tails the log starting at req.Offset, streaming each record
+// to the client as it becomes available. It blocks on the log's tail signal
+// when available, and falls back to polling otherwise.
+func (s *grpcServer) ConsumeStream(req *api.ConsumeRequest, stream api.Log_ConsumeStreamServer) error {
+	ctx := stream.Context()
+	offset := req.Offset
+	waiter, canWait := s.CommitLog.(tailWaiter)
+
+	for {
+		res, err := s.Consume(ctx, &api.ConsumeRequest{Offset: offset})
+		switch {
+		case err == nil:
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+			offset++
+			continue
+		case errors.Is(err, plog.ErrDeleted):
+			// The record at this offset was tombstoned; skip over it and
+			// keep tailing instead of killing an otherwise-healthy stream.
+			offset++
+			continue
+		case !errors.Is(err, plog.ErrOffsetOutOfRange):
+			// A genuine read failure (corrupt segment, I/O error) must
+			// reach the client, not be swallowed into an infinite wait for
+			// an offset that will never arrive.
+			return err
+		case canWait:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-waiter.Wait():
+			}
+		default:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pollInterval):
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	api "github.com/takumi616/proglog/api/v1"
+	plog "github.com/takumi616/proglog/internal/log"
+	"google.golang.org/grpc"
+)
+
+// fakeLog is a minimal CommitLog for exercising grpcServer without a real
+// on-disk log.
+type fakeLog struct {
+	records []*api.Record
+	deleted map[uint64]bool
+}
+
+func (f *fakeLog) Append(r *api.Record) (uint64, error) {
+	r.Offset = uint64(len(f.records))
+	f.records = append(f.records, r)
+	return r.Offset, nil
+}
+
+func (f *fakeLog) Read(off uint64) (*api.Record, error) {
+	if off >= uint64(len(f.records)) {
+		return nil, plog.ErrOffsetOutOfRange
+	}
+	if f.deleted[off] {
+		return nil, plog.ErrDeleted
+	}
+	return f.records[off], nil
+}
+
+// fakeProduceStream implements api.Log_ProduceStreamServer, embedding
+// grpc.ServerStream to satisfy the methods ProduceStream never calls.
+type fakeProduceStream struct {
+	grpc.ServerStream
+	reqs []*api.ProduceRequest
+	i    int
+	sent []*api.ProduceResponse
+}
+
+func (s *fakeProduceStream) Recv() (*api.ProduceRequest, error) {
+	if s.i >= len(s.reqs) {
+		return nil, io.EOF
+	}
+	req := s.reqs[s.i]
+	s.i++
+	return req, nil
+}
+
+func (s *fakeProduceStream) Send(res *api.ProduceResponse) error {
+	s.sent = append(s.sent, res)
+	return nil
+}
+
+func (s *fakeProduceStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestProduceStream_CleanEOF(t *testing.T) {
+	srv := &grpcServer{CommitLog: &fakeLog{}}
+	stream := &fakeProduceStream{reqs: []*api.ProduceRequest{
+		{Record: &api.Record{Value: []byte("a")}},
+		{Record: &api.Record{Value: []byte("b")}},
+	}}
+
+	// A well-behaved client calls CloseSend once it's done, which surfaces
+	// as io.EOF from Recv; that must end the RPC cleanly, not as an error.
+	if err := srv.ProduceStream(stream); err != nil {
+		t.Fatalf("ProduceStream returned %v, want nil on a clean client close", err)
+	}
+	if len(stream.sent) != 2 {
+		t.Fatalf("got %d responses, want 2", len(stream.sent))
+	}
+}
+
+// errStopStream lets fakeConsumeStream.Send end an otherwise infinite tail
+// loop deterministically once the test has seen enough records.
+var errStopStream = errors.New("stop")
+
+// fakeConsumeStream implements api.Log_ConsumeStreamServer.
+type fakeConsumeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*api.ConsumeResponse
+}
+
+func (s *fakeConsumeStream) Send(res *api.ConsumeResponse) error {
+	s.sent = append(s.sent, res)
+	if len(s.sent) >= 2 {
+		return errStopStream
+	}
+	return nil
+}
+
+func (s *fakeConsumeStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestConsumeStream_SkipsDeletedOffsets(t *testing.T) {
+	log := &fakeLog{deleted: map[uint64]bool{1: true}}
+	log.Append(&api.Record{Value: []byte("keep-0")})
+	log.Append(&api.Record{Value: []byte("deleted-1")})
+	log.Append(&api.Record{Value: []byte("keep-2")})
+
+	srv := &grpcServer{CommitLog: log}
+	stream := &fakeConsumeStream{ctx: context.Background()}
+
+	err := srv.ConsumeStream(&api.ConsumeRequest{Offset: 0}, stream)
+	if !errors.Is(err, errStopStream) {
+		t.Fatalf("ConsumeStream error = %v, want errStopStream", err)
+	}
+	if len(stream.sent) != 2 {
+		t.Fatalf("got %d responses, want 2 (offset 1 should have been skipped, not killed the stream)", len(stream.sent))
+	}
+	if string(stream.sent[0].Record.Value) != "keep-0" {
+		t.Fatalf("sent[0] = %q, want keep-0", stream.sent[0].Record.Value)
+	}
+	if string(stream.sent[1].Record.Value) != "keep-2" {
+		t.Fatalf("sent[1] = %q, want keep-2", stream.sent[1].Record.Value)
+	}
+}
+
+func TestConsumeStream_ReturnsCtxErrWhenOffsetNeverArrives(t *testing.T) {
+	srv := &grpcServer{CommitLog: &fakeLog{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream := &fakeConsumeStream{ctx: ctx}
+
+	if err := srv.ConsumeStream(&api.ConsumeRequest{Offset: 0}, stream); err == nil {
+		t.Fatal("expected ConsumeStream to return ctx.Err() once the context is done")
+	}
+}